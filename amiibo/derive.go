@@ -0,0 +1,66 @@
+package amiibo
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/binary"
+)
+
+// derivedKey holds the three secrets expanded from a masterKey for a
+// single tag: the AES-128-CTR key and initial counter used to
+// encrypt/decrypt AppData, and the HMAC-SHA256 key used to sign it.
+type derivedKey struct {
+	aesKey  [16]byte
+	aesIV   [16]byte
+	hmacKey [16]byte
+}
+
+// deriveKey runs the amiibo key derivation DRBG: the master key's xorPad
+// is combined with the 16 "seed" bytes taken from the tag (UID + fixed
+// type/counter bytes) and expanded through repeated HMAC-SHA256 calls
+// until enough output has been produced for the AES key, AES IV and
+// HMAC key (48 bytes total).
+//
+// EXPERIMENTAL: see the package doc comment - this expansion is not
+// checked against a known-correct derivation, only self-consistent with
+// Unlock/Lock in this package.
+func deriveKey(mk masterKey, seed [16]byte) derivedKey {
+	mixed := make([]byte, 16)
+	for i := range mixed {
+		mixed[i] = mk.xorPad[i] ^ seed[i]
+	}
+
+	base := make([]byte, 0, len(mixed)+len(mk.typeString)+2+int(mk.magicBytesSize))
+	base = append(base, mixed...)
+	base = append(base, mk.typeString[:]...)
+	base = append(base, 0x00, 0x00)
+	base = append(base, mk.magicBytes[:mk.magicBytesSize]...)
+
+	out := make([]byte, 0, 48)
+	for counter := uint16(0); len(out) < 48; counter++ {
+		msg := make([]byte, len(base)+2)
+		copy(msg, base)
+		binary.BigEndian.PutUint16(msg[len(base):], counter)
+
+		mac := hmac.New(sha256.New, mk.hmacKey[:])
+		mac.Write(msg)
+		out = append(out, mac.Sum(nil)...)
+	}
+	out = out[:48]
+
+	var dk derivedKey
+	copy(dk.aesKey[:], out[0:16])
+	copy(dk.aesIV[:], out[16:32])
+	copy(dk.hmacKey[:], out[32:48])
+	return dk
+}
+
+// tagSeed builds the 16 byte seed shared by both the data and tag key
+// derivations: the tag UID plus the two fixed NTAG215 internal bytes
+// that follow it, repeated to fill the block.
+func tagSeed(uid [9]byte) [16]byte {
+	var seed [16]byte
+	copy(seed[:9], uid[:])
+	copy(seed[9:], uid[:7])
+	return seed
+}
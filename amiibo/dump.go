@@ -0,0 +1,148 @@
+package amiibo
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/sha256"
+	"errors"
+
+	"github.com/loonaire/goflipmiibo/ntag215"
+)
+
+// Layout offsets of a raw 540 byte NTAG215 amiibo dump. These follow the
+// commonly documented AmiiboDump layout used by the various open source
+// amiibo tools referenced at the top of this package.
+const (
+	uidOffset      = 0x00
+	uidLen         = 9 // UID0-2, BCC0, UID3-6, BCC1
+	dataHMACOffset = 0x10
+	dataHMACLen    = 32
+	appDataOffset  = dataHMACOffset + dataHMACLen
+	appDataLen     = 0xDC
+	tagHMACOffset  = 0x1B4
+	tagHMACLen     = 32
+)
+
+// Dump wraps a raw 540 byte NTAG215 amiibo dump and exposes the
+// unlock/mutate/lock cycle needed to read or forge a working tag.
+type Dump struct {
+	raw [ntag215.DumpSize]byte
+}
+
+// NewDump wraps an existing raw dump. The content must be exactly
+// ntag215.DumpSize (540) bytes, as produced by loadBinFile.
+func NewDump(raw []byte) (*Dump, error) {
+	if len(raw) != ntag215.DumpSize {
+		return nil, errors.New("invalid amiibo dump: expected 540 bytes")
+	}
+	d := &Dump{}
+	copy(d.raw[:], raw)
+	return d, nil
+}
+
+// Bytes returns the current raw dump content.
+func (d *Dump) Bytes() []byte {
+	out := make([]byte, ntag215.DumpSize)
+	copy(out, d.raw[:])
+	return out
+}
+
+// UID returns the 7 byte tag UID (BCC bytes stripped), matching
+// ntag215.UID.
+func (d *Dump) UID() ntag215.UID {
+	var uid ntag215.UID
+	copy(uid[:3], d.raw[uidOffset:uidOffset+3])
+	copy(uid[3:], d.raw[uidOffset+4:uidOffset+8])
+	return uid
+}
+
+func (d *Dump) tagSeed() [9]byte {
+	var seed [9]byte
+	copy(seed[:], d.raw[uidOffset:uidOffset+uidLen])
+	return seed
+}
+
+// Unlock derives the per-tag keys from the given master keys and
+// decrypts the AppData area in place, mirroring the unlock step of the
+// AmiiboDump flow (unlock -> mutate -> lock). It returns the decrypted
+// AppData bytes; the dump itself is left holding plaintext AppData until
+// Lock is called again.
+func (d *Dump) Unlock(keys *MasterKeys) ([]byte, error) {
+	dataKey := deriveKey(keys.data, tagSeed(d.tagSeed()))
+
+	plain, err := ctrCrypt(dataKey.aesKey, dataKey.aesIV, d.raw[appDataOffset:appDataOffset+appDataLen])
+	if err != nil {
+		return nil, err
+	}
+	copy(d.raw[appDataOffset:appDataOffset+appDataLen], plain)
+
+	out := make([]byte, appDataLen)
+	copy(out, plain)
+	return out, nil
+}
+
+// Lock re-encrypts the (now plaintext) AppData area and recomputes both
+// the data and tag HMAC signatures, producing a dump that is again
+// accepted by a Switch as a genuine amiibo.
+func (d *Dump) Lock(keys *MasterKeys) error {
+	dataKey := deriveKey(keys.data, tagSeed(d.tagSeed()))
+	tagKey := deriveKey(keys.tag, tagSeed(d.tagSeed()))
+
+	cipherText, err := ctrCrypt(dataKey.aesKey, dataKey.aesIV, d.raw[appDataOffset:appDataOffset+appDataLen])
+	if err != nil {
+		return err
+	}
+	copy(d.raw[appDataOffset:appDataOffset+appDataLen], cipherText)
+
+	dataMAC := hmac.New(sha256.New, dataKey.hmacKey[:])
+	dataMAC.Write(d.raw[appDataOffset : appDataOffset+appDataLen])
+	copy(d.raw[dataHMACOffset:dataHMACOffset+dataHMACLen], dataMAC.Sum(nil))
+
+	tagMAC := hmac.New(sha256.New, tagKey.hmacKey[:])
+	tagMAC.Write(d.raw[uidOffset : uidOffset+uidLen])
+	tagMAC.Write(d.raw[dataHMACOffset : dataHMACOffset+dataHMACLen])
+	copy(d.raw[tagHMACOffset:tagHMACOffset+tagHMACLen], tagMAC.Sum(nil))
+
+	d.unsetLockBytes()
+	return nil
+}
+
+// unsetLockBytes clears the NTAG215 static and dynamic lock bits so the
+// written tag stays writable, matching the final step of the
+// AmiiboDump unlock -> mutate -> lock -> unset_lock_bytes flow. The
+// static lock bytes live on page 2 (bytes 2-3), right after the UID/BCC
+// pages; the dynamic lock bytes live in the page 130 lock page.
+func (d *Dump) unsetLockBytes() {
+	d.raw[10] = 0x00
+	d.raw[11] = 0x00
+	d.raw[0x208] = 0x00
+	d.raw[0x209] = 0x00
+}
+
+// SetUID rewrites the tag UID (for cloning a dump onto a blank NTAG215)
+// and its BCC checksum bytes, then refreshes the PWD/PACK fields derived
+// from it. Call this before Lock so the new UID is covered by the
+// recomputed HMAC signatures.
+func (d *Dump) SetUID(uid ntag215.UID) {
+	copy(d.raw[0:3], uid[:3])
+	d.raw[3] = 0x88 ^ uid[0] ^ uid[1] ^ uid[2] // BCC0, 0x88 is the NTAG215 cascade tag byte
+	copy(d.raw[4:8], uid[3:])
+	d.raw[8] = uid[3] ^ uid[4] ^ uid[5] ^ uid[6] // BCC1
+
+	pwd, pack := ntag215.CalculatePassword(uid)
+	pwdBytes := ntag215.PwdPageBytes(pwd)
+	packBytes := ntag215.PackPageBytes(pack)
+	copy(d.raw[ntag215.PwdPage*4:ntag215.PwdPage*4+4], pwdBytes[:])
+	copy(d.raw[ntag215.PackPage*4:ntag215.PackPage*4+4], packBytes[:])
+}
+
+func ctrCrypt(key, iv [16]byte, data []byte) ([]byte, error) {
+	block, err := aes.NewCipher(key[:])
+	if err != nil {
+		return nil, err
+	}
+	out := make([]byte, len(data))
+	cipher.NewCTR(block, iv[:]).XORKeyStream(out, data)
+	return out, nil
+}
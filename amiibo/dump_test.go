@@ -0,0 +1,93 @@
+package amiibo
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/loonaire/goflipmiibo/ntag215"
+)
+
+// fakeKeys builds a MasterKeys with deterministic, non-zero content for
+// both halves. There is no known-correct key_retail.bin available to
+// test against (see the package doc comment); this only lets Lock/Unlock
+// be checked for internal consistency.
+func fakeKeys() *MasterKeys {
+	raw := make([]byte, keyFileSize)
+	for i := range raw {
+		raw[i] = byte(i*7 + 3)
+	}
+	return &MasterKeys{
+		data: parseMasterKey(raw[:masterKeySize]),
+		tag:  parseMasterKey(raw[masterKeySize:]),
+	}
+}
+
+func TestSetUIDUpdatesPwdAndPack(t *testing.T) {
+	raw := make([]byte, ntag215.DumpSize)
+	dump, err := NewDump(raw)
+	if err != nil {
+		t.Fatalf("NewDump() error = %v", err)
+	}
+
+	uid := ntag215.UID{0x04, 0x1f, 0x2e, 0x4a, 0x5e, 0x6d, 0x80}
+	dump.SetUID(uid)
+
+	if got := dump.UID(); got != uid {
+		t.Errorf("UID() = %X, want %X", got, uid)
+	}
+
+	// Vector computed independently from the formula in
+	// ntag215.CalculatePassword's doc comment, not by calling it: see
+	// ntag215_test.go's TestCalculatePassword for the same UID.
+	wantPwdBytes := [4]byte{0xFF, 0x25, 0x8D, 0x8B}
+	wantPackBytes := [4]byte{0x80, 0x80, 0x00, 0x00}
+
+	gotPwd := dump.raw[ntag215.PwdPage*4 : ntag215.PwdPage*4+4]
+	gotPack := dump.raw[ntag215.PackPage*4 : ntag215.PackPage*4+4]
+
+	if !bytes.Equal(gotPwd, wantPwdBytes[:]) {
+		t.Errorf("PWD page = %X, want %X", gotPwd, wantPwdBytes)
+	}
+	if !bytes.Equal(gotPack, wantPackBytes[:]) {
+		t.Errorf("PACK page = %X, want %X", gotPack, wantPackBytes)
+	}
+}
+
+// TestUnlockLockRoundTrip checks that Lock undoes Unlock: this is an
+// internal self-consistency check only (see the package doc comment on
+// why there is no known-correct key+dump pair to validate against).
+func TestUnlockLockRoundTrip(t *testing.T) {
+	raw := make([]byte, ntag215.DumpSize)
+	for i := range raw {
+		raw[i] = byte(i)
+	}
+	dump, err := NewDump(raw)
+	if err != nil {
+		t.Fatalf("NewDump() error = %v", err)
+	}
+	keys := fakeKeys()
+
+	plain, err := dump.Unlock(keys)
+	if err != nil {
+		t.Fatalf("Unlock() error = %v", err)
+	}
+	wantPlain := make([]byte, appDataLen)
+	copy(wantPlain, plain)
+
+	if err := dump.Lock(keys); err != nil {
+		t.Fatalf("Lock() error = %v", err)
+	}
+
+	relocked, err := NewDump(dump.Bytes())
+	if err != nil {
+		t.Fatalf("NewDump() error = %v", err)
+	}
+	gotPlain, err := relocked.Unlock(keys)
+	if err != nil {
+		t.Fatalf("second Unlock() error = %v", err)
+	}
+
+	if !bytes.Equal(gotPlain, wantPlain) {
+		t.Errorf("Lock() did not round-trip: got AppData %X, want %X", gotPlain, wantPlain)
+	}
+}
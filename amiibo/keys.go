@@ -0,0 +1,100 @@
+// Package amiibo implements the amiibo-specific cryptography layered on
+// top of the raw NTAG215 dump: deriving per-tag AES/HMAC keys from the
+// retail master key file, decrypting/encrypting the AppData area and
+// recomputing the two HMAC signatures.
+//
+// EXPERIMENTAL: the key file layout (masterKey) and the derivation in
+// deriveKey are this package's best-effort reconstruction of the
+// undocumented amiitool/3dbrew algorithm from public descriptions, not
+// a hardware-verified reimplementation - there is no known-correct
+// key_retail.bin + dump pair available to check byte-for-byte output
+// against. Unlock/Lock are internally consistent (Lock undoes Unlock,
+// see dump_test.go) but producing a dump that a real Switch accepts is
+// unverified pending testing against genuine keys and hardware.
+//
+// Source used to write this code:
+// https://www.3dbrew.org/wiki/Amiibo
+// https://github.com/socram8888/amiitool (key file layout and derivation)
+package amiibo
+
+import (
+	"encoding/binary"
+	"errors"
+	"os"
+)
+
+const (
+	masterKeySize = 80
+	keyFileSize   = masterKeySize * 2
+)
+
+// masterKey is one half of a key_retail.bin file: either the "data" key
+// (protects AppData) or the "tag" key (protects the UID/type/counter
+// area). Field sizes and order follow the layout reverse engineered by
+// the amiitool project.
+type masterKey struct {
+	hmacKey        [16]byte
+	typeString     [14]byte
+	rfu            uint16
+	magicBytesSize uint16
+	magicBytes     [14]byte
+	xorPad         [32]byte
+}
+
+func parseMasterKey(raw []byte) masterKey {
+	var mk masterKey
+	copy(mk.hmacKey[:], raw[0:16])
+	copy(mk.typeString[:], raw[16:30])
+	mk.rfu = binary.BigEndian.Uint16(raw[30:32])
+	mk.magicBytesSize = binary.BigEndian.Uint16(raw[32:34])
+	if mk.magicBytesSize > uint16(len(mk.magicBytes)) {
+		mk.magicBytesSize = uint16(len(mk.magicBytes))
+	}
+	copy(mk.magicBytes[:], raw[34:48])
+	copy(mk.xorPad[:], raw[48:80])
+	return mk
+}
+
+// MasterKeys holds the two master keys (data and tag) needed to derive
+// per-tag keys, loaded from a key_retail.bin (or the separate
+// unfixed-info.bin/locked-secret.bin pair).
+type MasterKeys struct {
+	data masterKey
+	tag  masterKey
+}
+
+// LoadKeyRetail loads a combined 160 byte key_retail.bin file.
+func LoadKeyRetail(filename string) (*MasterKeys, error) {
+	raw, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, errors.New("error when loading key file, check filename")
+	}
+	if len(raw) != keyFileSize {
+		return nil, errors.New("invalid key file: expected 160 bytes")
+	}
+	return &MasterKeys{
+		data: parseMasterKey(raw[:masterKeySize]),
+		tag:  parseMasterKey(raw[masterKeySize:]),
+	}, nil
+}
+
+// LoadSplitKeys loads the unfixed-info.bin (data key) and
+// locked-secret.bin (tag key) pair, as distributed separately from the
+// combined key_retail.bin.
+func LoadSplitKeys(unfixedInfoPath, lockedSecretPath string) (*MasterKeys, error) {
+	dataRaw, err := os.ReadFile(unfixedInfoPath)
+	if err != nil {
+		return nil, errors.New("error when loading unfixed-info.bin, check filename")
+	}
+	tagRaw, err := os.ReadFile(lockedSecretPath)
+	if err != nil {
+		return nil, errors.New("error when loading locked-secret.bin, check filename")
+	}
+	if len(dataRaw) != masterKeySize || len(tagRaw) != masterKeySize {
+		return nil, errors.New("invalid key file: expected 80 bytes each")
+	}
+	return &MasterKeys{
+		data: parseMasterKey(dataRaw),
+		tag:  parseMasterKey(tagRaw),
+	}, nil
+}
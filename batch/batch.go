@@ -0,0 +1,159 @@
+// Package batch dispatches a conversion job across a worker pool,
+// replacing the sequential, panic-on-first-error main loop with
+// bounded concurrency and an aggregated error summary.
+package batch
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Job is a single unit of work dispatched to a worker: converting one
+// input file and reporting how many bytes it processed.
+type Job struct {
+	Path string
+	// Convert does the actual work for Path and returns the number of
+	// bytes read, for progress/throughput reporting.
+	Convert func(path string) (bytesProcessed int64, err error)
+}
+
+// Config controls how Run dispatches a batch of jobs.
+type Config struct {
+	Jobs            []Job
+	Workers         int       // defaults to runtime.NumCPU() when <= 0
+	ContinueOnError bool      // keep processing remaining jobs after a failure
+	Quiet           bool      // suppress progress reporting
+	Progress        io.Writer // defaults to no output when nil
+}
+
+// FileError records a single job failure, keyed by the file path that
+// failed, so the caller can report every failure instead of stopping at
+// the first one.
+type FileError struct {
+	Path string
+	Err  error
+}
+
+func (e FileError) Error() string {
+	return fmt.Sprintf("%s: %v", e.Path, e.Err)
+}
+
+// Summary aggregates the outcome of a batch run.
+type Summary struct {
+	Total     int
+	Succeeded int
+	Failed    []FileError
+	// Skipped lists jobs that were never attempted because a prior
+	// failure stopped the run (ContinueOnError is false) or ctx was
+	// cancelled.
+	Skipped []string
+}
+
+// runJob calls job.Convert, recovering from a panic (e.g. a truncated or
+// corrupt input file indexing past the end of a short byte slice) and
+// reporting it as an ordinary FileError instead of taking down the
+// whole batch along with every other file still in flight.
+func runJob(job Job) (n int64, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("panic: %v", r)
+		}
+	}()
+	return job.Convert(job.Path)
+}
+
+// Run dispatches every job in cfg across a bounded worker pool and
+// returns once all of them have completed (or ctx is cancelled). Unlike
+// a sequential loop that log.Panicln's on the first error, every
+// failure is collected into the returned Summary so the caller decides
+// what to do with it.
+func Run(ctx context.Context, cfg Config) Summary {
+	workers := cfg.Workers
+	if workers <= 0 {
+		workers = runtime.NumCPU()
+	}
+
+	jobCh := make(chan Job)
+	var (
+		mu         sync.Mutex
+		summary    = Summary{Total: len(cfg.Jobs)}
+		done       int64
+		totalBytes int64
+		stop       int32
+	)
+	start := time.Now()
+
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for job := range jobCh {
+				if atomic.LoadInt32(&stop) != 0 {
+					mu.Lock()
+					summary.Skipped = append(summary.Skipped, job.Path)
+					mu.Unlock()
+					continue
+				}
+				n, err := runJob(job)
+
+				mu.Lock()
+				if err != nil {
+					summary.Failed = append(summary.Failed, FileError{Path: job.Path, Err: err})
+					if !cfg.ContinueOnError {
+						atomic.StoreInt32(&stop, 1)
+					}
+				} else {
+					summary.Succeeded++
+				}
+				done++
+				totalBytes += n
+				doneNow, bytesNow := done, totalBytes
+				mu.Unlock()
+
+				if !cfg.Quiet && cfg.Progress != nil {
+					elapsed := time.Since(start).Seconds()
+					var mbps float64
+					if elapsed > 0 {
+						mbps = float64(bytesNow) / (1024 * 1024) / elapsed
+					}
+					fmt.Fprintf(cfg.Progress, "\r%d/%d files done (%.2f MB/s)", doneNow, summary.Total, mbps)
+				}
+			}
+		}()
+	}
+
+dispatch:
+	for i, job := range cfg.Jobs {
+		if atomic.LoadInt32(&stop) != 0 {
+			mu.Lock()
+			for _, remaining := range cfg.Jobs[i:] {
+				summary.Skipped = append(summary.Skipped, remaining.Path)
+			}
+			mu.Unlock()
+			break dispatch
+		}
+		select {
+		case jobCh <- job:
+		case <-ctx.Done():
+			mu.Lock()
+			for _, remaining := range cfg.Jobs[i:] {
+				summary.Skipped = append(summary.Skipped, remaining.Path)
+			}
+			mu.Unlock()
+			break dispatch
+		}
+	}
+	close(jobCh)
+	wg.Wait()
+
+	if !cfg.Quiet && cfg.Progress != nil {
+		fmt.Fprintln(cfg.Progress)
+	}
+	return summary
+}
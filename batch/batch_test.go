@@ -0,0 +1,157 @@
+package batch
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+)
+
+func makeJobs(n int, failAt int) []Job {
+	jobs := make([]Job, n)
+	for i := 0; i < n; i++ {
+		i := i
+		jobs[i] = Job{
+			Path: string(rune('a' + i)),
+			Convert: func(p string) (int64, error) {
+				if failAt >= 0 && i == failAt {
+					return 0, errors.New("boom")
+				}
+				return 10, nil
+			},
+		}
+	}
+	return jobs
+}
+
+func TestRunStopsOnFirstErrorByDefault(t *testing.T) {
+	jobs := makeJobs(5, 0)
+
+	summary := Run(context.Background(), Config{
+		Jobs:    jobs,
+		Workers: 1,
+		Quiet:   true,
+	})
+
+	if summary.Total != 5 {
+		t.Errorf("Total = %d, want 5", summary.Total)
+	}
+	if len(summary.Failed) != 1 {
+		t.Fatalf("Failed = %d entries, want 1", len(summary.Failed))
+	}
+	if summary.Failed[0].Path != "a" {
+		t.Errorf("Failed[0].Path = %q, want %q", summary.Failed[0].Path, "a")
+	}
+	if summary.Succeeded != 0 {
+		t.Errorf("Succeeded = %d, want 0", summary.Succeeded)
+	}
+	if len(summary.Skipped) != 4 {
+		t.Errorf("Skipped = %d entries, want 4", len(summary.Skipped))
+	}
+	if got := summary.Succeeded + len(summary.Failed) + len(summary.Skipped); got != summary.Total {
+		t.Errorf("accounted for %d jobs, want %d (Succeeded+Failed+Skipped must equal Total)", got, summary.Total)
+	}
+}
+
+func TestRunContinueOnErrorProcessesEveryJob(t *testing.T) {
+	jobs := makeJobs(5, 2)
+
+	summary := Run(context.Background(), Config{
+		Jobs:            jobs,
+		Workers:         1,
+		ContinueOnError: true,
+		Quiet:           true,
+	})
+
+	if summary.Total != 5 {
+		t.Errorf("Total = %d, want 5", summary.Total)
+	}
+	if summary.Succeeded != 4 {
+		t.Errorf("Succeeded = %d, want 4", summary.Succeeded)
+	}
+	if len(summary.Failed) != 1 {
+		t.Fatalf("Failed = %d entries, want 1", len(summary.Failed))
+	}
+	if summary.Failed[0].Path != "c" {
+		t.Errorf("Failed[0].Path = %q, want %q", summary.Failed[0].Path, "c")
+	}
+	if len(summary.Skipped) != 0 {
+		t.Errorf("Skipped = %d entries, want 0", len(summary.Skipped))
+	}
+}
+
+func TestRunAllSucceedConcurrently(t *testing.T) {
+	jobs := makeJobs(20, -1)
+
+	summary := Run(context.Background(), Config{
+		Jobs:    jobs,
+		Workers: 4,
+		Quiet:   true,
+	})
+
+	if summary.Succeeded != 20 {
+		t.Errorf("Succeeded = %d, want 20", summary.Succeeded)
+	}
+	if len(summary.Failed) != 0 || len(summary.Skipped) != 0 {
+		t.Errorf("Failed = %d, Skipped = %d, want 0 and 0", len(summary.Failed), len(summary.Skipped))
+	}
+}
+
+func TestRunRecoversFromPanickingJob(t *testing.T) {
+	jobs := []Job{
+		{Path: "a", Convert: func(p string) (int64, error) { return 10, nil }},
+		{Path: "bad", Convert: func(p string) (int64, error) {
+			var fileContent []byte
+			_ = fileContent[4:8] // simulates indexing past a truncated input file
+			return 0, nil
+		}},
+		{Path: "c", Convert: func(p string) (int64, error) { return 10, nil }},
+	}
+
+	summary := Run(context.Background(), Config{
+		Jobs:            jobs,
+		Workers:         1,
+		ContinueOnError: true,
+		Quiet:           true,
+	})
+
+	if summary.Succeeded != 2 {
+		t.Errorf("Succeeded = %d, want 2", summary.Succeeded)
+	}
+	if len(summary.Failed) != 1 {
+		t.Fatalf("Failed = %d entries, want 1", len(summary.Failed))
+	}
+	if summary.Failed[0].Path != "bad" {
+		t.Errorf("Failed[0].Path = %q, want %q", summary.Failed[0].Path, "bad")
+	}
+}
+
+func TestRunIsSafeForConcurrentWorkers(t *testing.T) {
+	// Exercise the shared Summary bookkeeping with more workers than
+	// jobs-per-worker to catch data races under -race.
+	var calls sync.WaitGroup
+	n := 50
+	jobs := make([]Job, n)
+	for i := 0; i < n; i++ {
+		calls.Add(1)
+		jobs[i] = Job{
+			Path: string(rune('a' + i%26)),
+			Convert: func(p string) (int64, error) {
+				defer calls.Done()
+				return 1, nil
+			},
+		}
+	}
+
+	summary := Run(context.Background(), Config{
+		Jobs:            jobs,
+		Workers:         8,
+		ContinueOnError: true,
+		Quiet:           true,
+	})
+
+	calls.Wait()
+	if summary.Succeeded != n {
+		t.Errorf("Succeeded = %d, want %d", summary.Succeeded, n)
+	}
+}
@@ -0,0 +1,232 @@
+// Package convert turns raw NTAG215 dumps into the Flipper "NFC device"
+// text format (and back), and into the handful of other dump formats
+// used across the NFC tooling ecosystem.
+//
+// Source used to write this code:
+// https://github.com/flipperdevices/flipperzero-firmware (lib/nfc/protocols/nfca)
+package convert
+
+import (
+	"bufio"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/loonaire/goflipmiibo/ntag215"
+)
+
+// Format selects which dump representation an Encoder produces.
+type Format string
+
+const (
+	FormatFlipper2 Format = "flipper2"
+	FormatFlipper4 Format = "flipper4"
+	FormatPM3      Format = "pm3"
+	FormatEML      Format = "eml"
+)
+
+// Encoder renders a raw NTAG215 dump into a specific on-disk format.
+type Encoder interface {
+	Encode(uid string, pages []string) string
+}
+
+// NewEncoder returns the Encoder for the given format, or an error if
+// the format is unknown.
+func NewEncoder(format Format) (Encoder, error) {
+	switch format {
+	case FormatFlipper2:
+		return flipper2Encoder{}, nil
+	case FormatFlipper4:
+		return flipper4Encoder{}, nil
+	case FormatPM3:
+		return pm3Encoder{}, nil
+	case FormatEML:
+		return emlEncoder{}, nil
+	default:
+		return nil, fmt.Errorf("unknown format %q", format)
+	}
+}
+
+// PagesToStrings renders a raw dump into the "AA BB CC DD" per-page
+// strings shared by every encoder in this package. Short dumps are
+// zero-padded and long ones truncated to ntag215.DumpSize, and pages 133
+// (PWD) and 134 (PACK) are always recomputed from the dump's own UID,
+// the same way the original converter patched up the password pages on
+// every conversion.
+func PagesToStrings(content []byte) []string {
+	normalized := RecomputePasswordPages(content)
+
+	pages := make([]string, 0, ntag215.PageQuantity)
+	for i := 0; i < ntag215.DumpSize; i += ntag215.PageSize {
+		words := make([]string, ntag215.PageSize)
+		for j := 0; j < ntag215.PageSize; j++ {
+			words[j] = strings.ToUpper(hex.EncodeToString(normalized[i+j : i+j+1]))
+		}
+		pages = append(pages, strings.Join(words, " "))
+	}
+	return pages
+}
+
+// RecomputePasswordPages pads or truncates content to ntag215.DumpSize
+// and returns a copy with pages 133 and 134 overwritten by the PWD/PACK
+// derived from its own UID. Exposed so callers comparing a conversion's
+// output against its input (e.g. round-trip verification) can build the
+// same expected password bytes rather than flagging the normalization
+// as a mismatch.
+func RecomputePasswordPages(content []byte) []byte {
+	normalized := PadToDumpSize(content)
+	ntag215.RecomputePasswordPages(normalized)
+	return normalized
+}
+
+// PadToDumpSize returns a copy of content zero-padded (or truncated) to
+// ntag215.DumpSize, with no other changes. Callers that only need a
+// dump-sized, panic-safe byte slice - e.g. reading the UID out of a
+// possibly truncated input file - should use this directly rather than
+// RecomputePasswordPages, which does the extra work of recomputing the
+// PWD/PACK crypto pages.
+func PadToDumpSize(content []byte) []byte {
+	normalized := make([]byte, ntag215.DumpSize)
+	copy(normalized, content)
+	return normalized
+}
+
+type flipper2Encoder struct{}
+
+func (flipper2Encoder) Encode(uid string, pages []string) string {
+	numbered := make([]string, len(pages))
+	for i, p := range pages {
+		numbered[i] = "Page " + strconv.Itoa(i) + ": " + p
+	}
+	return fmt.Sprintf(`Filetype: Flipper NFC device
+Version: 2
+# Nfc device type can be UID, Mifare Ultralight, Bank card
+Device type: NTAG215
+# UID, ATQA and SAK are common for all formats
+UID: %s
+ATQA: 44 00
+SAK: 00
+# Mifare Ultralight specific data
+Signature: 00 00 00 00 00 00 00 00 00 00 00 00 00 00 00 00 00 00 00 00 00 00 00 00 00 00 00 00 00 00 00 00
+Mifare version: 00 04 04 02 01 00 11 03
+Counter 0: 0
+Tearing 0: 00
+Counter 1: 0
+Tearing 1: 00
+Counter 2: 0
+Tearing 2: 00
+Pages total: %d
+%s`, uid, ntag215.PageQuantity, strings.Join(numbered, "\n"))
+}
+
+type flipper4Encoder struct{}
+
+func (flipper4Encoder) Encode(uid string, pages []string) string {
+	numbered := make([]string, len(pages))
+	for i, p := range pages {
+		numbered[i] = "Page " + strconv.Itoa(i) + ": " + p
+	}
+	return fmt.Sprintf(`Filetype: Flipper NFC device
+Version: 4
+# Nfc device type can be UID, Mifare Ultralight, Bank card
+Device type: NTAG215
+# UID, ATQA and SAK are common for all formats
+UID: %s
+ATQA: 44 00
+SAK: 00
+# Mifare Ultralight specific data
+Data format version: 1
+Signature: 00 00 00 00 00 00 00 00 00 00 00 00 00 00 00 00 00 00 00 00 00 00 00 00 00 00 00 00 00 00 00 00
+Mifare version: 00 04 04 02 01 00 11 03
+Counter 0: 0
+Tearing 0: 00
+Counter 1: 0
+Tearing 1: 00
+Counter 2: 0
+Tearing 2: 00
+Failed authentication attempts: 0
+Pages total: %d
+%s`, uid, ntag215.PageQuantity, strings.Join(numbered, "\n"))
+}
+
+type pm3Encoder struct{}
+
+func (pm3Encoder) Encode(uid string, pages []string) string {
+	blocks := make([]string, len(pages))
+	for i, p := range pages {
+		blocks[i] = fmt.Sprintf("    \"%d\": \"%s\"", i, strings.ReplaceAll(p, " ", ""))
+	}
+	return fmt.Sprintf(`{
+  "Created": "goflipmiibo",
+  "FileType": "mfu",
+  "Card": {
+    "UID": "%s"
+  },
+  "blocks": {
+%s
+  }
+}`, strings.ReplaceAll(uid, " ", ""), strings.Join(blocks, ",\n"))
+}
+
+type emlEncoder struct{}
+
+func (emlEncoder) Encode(_ string, pages []string) string {
+	lines := make([]string, len(pages))
+	for i, p := range pages {
+		lines[i] = strings.ReplaceAll(p, " ", "")
+	}
+	return strings.Join(lines, "\n")
+}
+
+// ConvertNfcToBin parses a Flipper "NFC device" text file (handling both
+// the Version 2 and Version 4 page layouts) and reassembles the raw
+// NTAG215 dump it describes.
+func ConvertNfcToBin(content string) ([]byte, error) {
+	scanner := bufio.NewScanner(strings.NewReader(content))
+	pages := make(map[int][]byte)
+	maxPage := -1
+
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if !strings.HasPrefix(line, "Page ") {
+			continue
+		}
+		rest := strings.TrimPrefix(line, "Page ")
+		sep := strings.Index(rest, ":")
+		if sep < 0 {
+			continue
+		}
+		pageNum, err := strconv.Atoi(strings.TrimSpace(rest[:sep]))
+		if err != nil {
+			return nil, fmt.Errorf("invalid page number in line %q: %w", line, err)
+		}
+		hexPart := strings.ReplaceAll(strings.TrimSpace(rest[sep+1:]), " ", "")
+		bytesRead, err := hex.DecodeString(hexPart)
+		if err != nil {
+			return nil, fmt.Errorf("invalid page bytes in line %q: %w", line, err)
+		}
+		if len(bytesRead) != ntag215.PageSize {
+			return nil, fmt.Errorf("page %d has %d bytes, want %d", pageNum, len(bytesRead), ntag215.PageSize)
+		}
+		pages[pageNum] = bytesRead
+		if pageNum > maxPage {
+			maxPage = pageNum
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if maxPage < 0 {
+		return nil, errors.New("no pages found in nfc file")
+	}
+
+	out := make([]byte, ntag215.DumpSize)
+	for i := 0; i < ntag215.PageQuantity; i++ {
+		if page, ok := pages[i]; ok {
+			copy(out[i*ntag215.PageSize:], page)
+		}
+	}
+	return out, nil
+}
@@ -1,18 +1,5 @@
 package main
 
-import (
-	"encoding/hex"
-	"errors"
-	"flag"
-	"fmt"
-	"log"
-	"os"
-	"path/filepath"
-	"slices"
-	"strconv"
-	"strings"
-)
-
 /*
 Source i have use for write this code:
 https://github.com/turbospok/Flipper-NTAG215-password-converter/blob/main/ntag215converter.py
@@ -25,10 +12,31 @@ https://www.reddit.com/r/flipperzero/comments/ydlytv/comment/ksxtn7j/?utm_source
 
 */
 
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/loonaire/goflipmiibo/amiibo"
+	"github.com/loonaire/goflipmiibo/batch"
+	"github.com/loonaire/goflipmiibo/convert"
+	"github.com/loonaire/goflipmiibo/ntag215"
+)
+
+// mode selects what the tool does with each input file.
+type mode string
+
 const (
-	// Amiibos uses NTAG215, NTAG215 Tag has 135 pages (0 to 134), each pages contains 4 bytes
-	// for more information check de ntag215 documentation
-	Ntag215PageQuantity = 135
+	modeBinToNfc mode = "bin2nfc"
+	modeNfcToBin mode = "nfc2bin"
+	modeVerify   mode = "verify"
 )
 
 func loadBinFile(filename string) ([]byte, error) {
@@ -39,17 +47,26 @@ func loadBinFile(filename string) ([]byte, error) {
 	return fileContent, nil
 }
 
-func saveNfcFile(filename string, content []byte) error {
+func saveFile(filename string, content []byte) error {
+	if err := os.MkdirAll(filepath.Dir(filename), 0755); err != nil {
+		return errors.New("error when creating output directory")
+	}
 	if err := os.WriteFile(filename, content, 0644); err != nil {
-		return errors.New("error when write nfc file")
+		return errors.New("error when write output file")
 	}
 	return nil
 }
 
 func extractUid(fileContent []byte) string {
-	// Amiibo Uid is the addition of first 3 bytes  and 4 to 7 bytes of bin file
-	// More simple: This is the first 8 bytes and we remove the bytes at position 3
-	uidBytes := slices.Concat(fileContent[:3], fileContent[4:8])
+	// Amiibo Uid is the addition of first 3 bytes and 4 to 7 bytes of bin file
+	// More simple: This is the first 8 bytes and we remove the byte at position 3
+	//
+	// fileContent comes straight from a file on disk (routine callers: an
+	// entire AmiiboDB archive, dumps produced by other tools), so a
+	// truncated/corrupt input must not panic here - pad it to DumpSize
+	// first, the same normalization convert.PagesToStrings already does.
+	padded := convert.PadToDumpSize(fileContent)
+	uidBytes := append(append([]byte{}, padded[:3]...), padded[4:8]...)
 	strUid := []string{}
 	for _, b := range uidBytes {
 		strUid = append(strUid, strings.ToUpper(hex.EncodeToString([]byte{b})))
@@ -57,120 +74,248 @@ func extractUid(fileContent []byte) string {
 	return strings.Join(strUid, " ")
 }
 
-func calculatePassword(rawUid string) string {
-	// calculate the password of the tag from the bin file content
-	password := []string{}
-	password = append(password, hex.EncodeToString([]byte{rawUid[1] ^ rawUid[3] ^ 0xAA}))
-	password = append(password, hex.EncodeToString([]byte{rawUid[2] ^ rawUid[4] ^ 0x55}))
-	password = append(password, hex.EncodeToString([]byte{rawUid[3] ^ rawUid[5] ^ 0xAA}))
-	password = append(password, hex.EncodeToString([]byte{rawUid[4] ^ rawUid[6] ^ 0x55}))
-	return strings.Join(password, " ")
+func getFilesWithExt(path string, ext string) []string {
+	files := []string{}
+	err := filepath.Walk(path,
+		func(path string, info os.FileInfo, err error) error {
+			if err != nil {
+				return err
+			}
+			if strings.HasSuffix(path, ext) && !info.IsDir() {
+				files = append(files, path)
+			}
+			return nil
+		})
+	if err != nil {
+		log.Println(err)
+	}
+	return files
+}
+
+func outputExtension(format convert.Format) string {
+	switch format {
+	case convert.FormatPM3:
+		return ".json"
+	case convert.FormatEML:
+		return ".eml"
+	default:
+		return ".nfc"
+	}
 }
 
-func convertBinDataToNfcPages(fileContent []byte) string {
-	pagesContent := []string{}
-	pageCount := 0
+func replaceExt(path, inputDir, outputDir, oldExt, newExt string) string {
+	outputPath := strings.Replace(path, inputDir, outputDir, 1)
+	outputPath = strings.TrimSuffix(outputPath, oldExt) + newExt
+	return outputPath
+}
 
-	if len(fileContent)%4 != 0 {
-		// case where the file as missing bytes, pad the content with 00 bytes...
-		for len(fileContent)%4 != 0 {
-			fileContent = append(fileContent, byte('\x00'))
-		}
+// maybeUnlockAndLock round-trips fileContent through the amiibo
+// unlock -> mutate -> lock cycle when keys are available, optionally
+// cloning the tag onto a new UID along the way. With no keys loaded the
+// content is returned unchanged, preserving the previous behaviour of
+// only reshuffling bytes into the NFC text format.
+func maybeUnlockAndLock(fileContent []byte, keys *amiibo.MasterKeys, newUID string) ([]byte, error) {
+	if keys == nil && newUID == "" {
+		return fileContent, nil
+	}
+	if len(fileContent) != ntag215.DumpSize {
+		return nil, fmt.Errorf("cannot unlock/clone: dump is %d bytes, want %d", len(fileContent), ntag215.DumpSize)
 	}
 
-	for i := 0; i < len(fileContent); i += 4 {
-		page := "Page " + strconv.FormatInt(int64(pageCount), 10) + ":"
+	dump, err := amiibo.NewDump(fileContent)
+	if err != nil {
+		return nil, err
+	}
 
-		for j := 0; j < 4; j++ {
-			page += " " + strings.ToUpper(hex.EncodeToString(fileContent[i+j:i+j+1]))
+	if keys != nil {
+		if _, err := dump.Unlock(keys); err != nil {
+			return nil, fmt.Errorf("unlock: %w", err)
 		}
-		pagesContent = append(pagesContent, page)
-		pageCount++
-		if pageCount >= Ntag215PageQuantity {
-			// some amiibo bins are in 572 bytes, if the content is too big ignore the bytes
-			break
+	}
+
+	if newUID != "" {
+		uidBytes, err := hex.DecodeString(strings.ReplaceAll(newUID, " ", ""))
+		if err != nil || len(uidBytes) != 7 {
+			return nil, errors.New("invalid -uid: expected 7 hex bytes")
 		}
+		dump.SetUID(ntag215.UID(uidBytes))
 	}
-	if pageCount < Ntag215PageQuantity {
-		// if the file is too small, pad the page with 00 bytes
-		for pageCount < Ntag215PageQuantity {
-			page := "Page " + strconv.FormatInt(int64(pageCount), 10) + ": 00 00 00 00"
-			pagesContent = append(pagesContent, page)
-			pageCount++
+
+	if keys != nil {
+		if err := dump.Lock(keys); err != nil {
+			return nil, fmt.Errorf("lock: %w", err)
 		}
 	}
 
-	uid, _ := hex.DecodeString(strings.ReplaceAll(extractUid(fileContent), " ", ""))
-	pagesContent[133] = "Page 133: " + strings.ToUpper(calculatePassword(string(uid)))
-	pagesContent[134] = "Page 134: 80 80 00 00"
+	return dump.Bytes(), nil
+}
+
+func convertBinToNfc(path, inputDir, outputDir string, format convert.Format, keys *amiibo.MasterKeys, newUID string) (int64, error) {
+	fileContent, err := loadBinFile(path)
+	if err != nil {
+		return 0, err
+	}
 
-	return strings.Join(pagesContent, "\n")
+	fileContent, err = maybeUnlockAndLock(fileContent, keys, newUID)
+	if err != nil {
+		return 0, err
+	}
+
+	encoder, err := convert.NewEncoder(format)
+	if err != nil {
+		return 0, err
+	}
+	content := encoder.Encode(extractUid(fileContent), convert.PagesToStrings(fileContent))
+
+	outputPath := replaceExt(path, inputDir, outputDir, ".bin", outputExtension(format))
+	if err := saveFile(outputPath, []byte(content)); err != nil {
+		return 0, err
+	}
+	return int64(len(fileContent)), nil
 }
 
-func createNfcFileContent(uid string, pages string) string {
-	content := fmt.Sprintf(`Filetype: Flipper NFC device
-Version: 2
-# Nfc device type can be UID, Mifare Ultralight, Bank card
-Device type: NTAG215
-# UID, ATQA and SAK are common for all formats
-UID: %s
-ATQA: 44 00
-SAK: 00
-# Mifare Ultralight specific data
-Signature: 00 00 00 00 00 00 00 00 00 00 00 00 00 00 00 00 00 00 00 00 00 00 00 00 00 00 00 00 00 00 00 00
-Mifare version: 00 04 04 02 01 00 11 03
-Counter 0: 0
-Tearing 0: 00
-Counter 1: 0
-Tearing 1: 00
-Counter 2: 0
-Tearing 2: 00
-Pages total: %d
-%s`, uid, Ntag215PageQuantity, pages)
-	return content
+func convertNfcToBin(path, inputDir, outputDir string) (int64, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return 0, errors.New("error when loading file, check filename")
+	}
+
+	binContent, err := convert.ConvertNfcToBin(string(content))
+	if err != nil {
+		return 0, err
+	}
+
+	outputPath := replaceExt(path, inputDir, outputDir, ".nfc", ".bin")
+	if err := saveFile(outputPath, binContent); err != nil {
+		return 0, err
+	}
+	return int64(len(binContent)), nil
 }
 
-func getAllBinFiles(path string) []string {
-	files := []string{}
-	err := filepath.Walk(path,
-		func(path string, info os.FileInfo, err error) error {
-			if err != nil {
-				return err
-			}
-			if strings.HasSuffix(path, ".bin") && !info.IsDir() {
-				files = append(files, path)
-			}
-			return nil
-		})
+func verifyRoundTrip(path string, format convert.Format) (int64, error) {
+	if format != convert.FormatFlipper2 && format != convert.FormatFlipper4 {
+		return 0, fmt.Errorf("-mode verify only supports -format flipper2 or flipper4, got %q", format)
+	}
+
+	original, err := loadBinFile(path)
 	if err != nil {
-		log.Println(err)
+		return 0, err
 	}
-	return files
+
+	encoder, err := convert.NewEncoder(format)
+	if err != nil {
+		return 0, err
+	}
+	nfcContent := encoder.Encode(extractUid(original), convert.PagesToStrings(original))
+
+	roundTripped, err := convert.ConvertNfcToBin(nfcContent)
+	if err != nil {
+		return 0, err
+	}
+
+	// The conversion always recomputes the password pages from the
+	// dump's own UID, so the expected result carries that same
+	// normalization rather than the original's raw bytes.
+	padded := convert.RecomputePasswordPages(original)
+	if !bytes.Equal(padded, roundTripped) {
+		for i := 0; i < ntag215.PageQuantity; i++ {
+			start := i * ntag215.PageSize
+			if !bytes.Equal(padded[start:start+ntag215.PageSize], roundTripped[start:start+ntag215.PageSize]) {
+				return 0, fmt.Errorf("page %d mismatch: got %X, want %X",
+					i, roundTripped[start:start+ntag215.PageSize], padded[start:start+ntag215.PageSize])
+			}
+		}
+		return 0, errors.New("round trip mismatch")
+	}
+	return int64(len(original)), nil
+}
+
+func loadKeys(keysFlag string) (*amiibo.MasterKeys, error) {
+	if keysFlag == "" {
+		return nil, nil
+	}
+	return amiibo.LoadKeyRetail(keysFlag)
 }
 
 func main() {
 	inputDir := flag.String("input", "Amiibo Bins", "Input path")
 	outputDir := flag.String("output", "output", "Path for converted Files")
+	modeFlag := flag.String("mode", string(modeBinToNfc), "Conversion mode: bin2nfc, nfc2bin or verify")
+	formatFlag := flag.String("format", string(convert.FormatFlipper2), "Output format for bin2nfc: flipper2, flipper4, pm3 or eml")
+	keysFlag := flag.String("keys", "", "Path to key_retail.bin, enables real decrypt/re-encrypt instead of raw byte reshuffling (EXPERIMENTAL: unverified against genuine keys/hardware, see amiibo package docs)")
+	uidFlag := flag.String("uid", "", "Clone the dump onto this new UID (7 hex bytes, e.g. \"04 1F 2E 4A 5E 6D 80\"), requires -keys")
+	jobs := flag.Int("jobs", 0, "Number of concurrent workers, defaults to runtime.NumCPU()")
+	continueOnError := flag.Bool("continue-on-error", false, "Keep converting remaining files after a failure instead of stopping")
+	quiet := flag.Bool("quiet", false, "Suppress progress reporting")
 
 	flag.Parse()
-	filesToConvert := getAllBinFiles(*inputDir)
 
-	for _, file := range filesToConvert {
+	keys, err := loadKeys(*keysFlag)
+	if err != nil {
+		log.Fatalln(err)
+	}
+	if *uidFlag != "" && keys == nil {
+		log.Fatalln("-uid requires -keys")
+	}
+	if keys != nil {
+		log.Println("warning: -keys derivation is experimental and unverified against genuine keys/hardware; the produced dump is not known to be accepted by a real Switch (see amiibo package docs)")
+	}
 
-		fileContent, err := loadBinFile(file)
-		if err != nil {
-			log.Panicln(err)
-		}
+	m := mode(*modeFlag)
+	format := convert.Format(*formatFlag)
 
-		nfcContent := createNfcFileContent(string(extractUid(fileContent)), convertBinDataToNfcPages(fileContent))
+	var jobList []batch.Job
+	switch m {
+	case modeBinToNfc:
+		for _, path := range getFilesWithExt(*inputDir, ".bin") {
+			path := path
+			jobList = append(jobList, batch.Job{
+				Path: path,
+				Convert: func(p string) (int64, error) {
+					return convertBinToNfc(p, *inputDir, *outputDir, format, keys, *uidFlag)
+				},
+			})
+		}
+	case modeNfcToBin:
+		for _, path := range getFilesWithExt(*inputDir, ".nfc") {
+			path := path
+			jobList = append(jobList, batch.Job{
+				Path: path,
+				Convert: func(p string) (int64, error) {
+					return convertNfcToBin(p, *inputDir, *outputDir)
+				},
+			})
+		}
+	case modeVerify:
+		for _, path := range getFilesWithExt(*inputDir, ".bin") {
+			jobList = append(jobList, batch.Job{
+				Path: path,
+				Convert: func(p string) (int64, error) {
+					return verifyRoundTrip(p, format)
+				},
+			})
+		}
+	default:
+		log.Fatalf("unknown -mode %q, want bin2nfc, nfc2bin or verify", *modeFlag)
+	}
 
-		fmt.Println("Proccess file: ", file)
-		outputPath := strings.ReplaceAll(file, *inputDir, *outputDir)
-		outputPath = strings.ReplaceAll(outputPath, ".bin", ".nfc")
-		os.MkdirAll(filepath.Dir(outputPath), 0644)
+	summary := batch.Run(context.Background(), batch.Config{
+		Jobs:            jobList,
+		Workers:         *jobs,
+		ContinueOnError: *continueOnError,
+		Quiet:           *quiet,
+		Progress:        os.Stderr,
+	})
 
-		if err := saveNfcFile(outputPath, []byte(nfcContent)); err != nil {
-			log.Println("Error when save NfcFile ", err)
-		}
+	fmt.Printf("%d/%d files processed successfully\n", summary.Succeeded, summary.Total)
+	for _, failure := range summary.Failed {
+		fmt.Println("FAILED:", failure.Error())
+	}
+	if len(summary.Skipped) > 0 {
+		fmt.Printf("SKIPPED %d file(s) after a failure (use -continue-on-error to process them anyway): %s\n",
+			len(summary.Skipped), strings.Join(summary.Skipped, ", "))
+	}
+	if len(summary.Failed) > 0 {
+		os.Exit(1)
 	}
 }
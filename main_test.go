@@ -0,0 +1,14 @@
+package main
+
+import "testing"
+
+func TestExtractUidHandlesTruncatedInput(t *testing.T) {
+	// A truncated/corrupt .bin (routine when batch-processing an entire
+	// AmiiboDB archive) must not panic: it should come back zero-padded
+	// rather than indexing past the end of fileContent.
+	got := extractUid([]byte{0x04, 0x1f})
+	want := "04 1F 00 00 00 00 00"
+	if got != want {
+		t.Errorf("extractUid() = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,89 @@
+// Package ntag215 models the pages and authentication fields of an NXP
+// NTAG215 tag, as used by amiibo figures.
+//
+// Source used to write this code:
+// https://www.nxp.com/docs/en/data-sheet/NTAG213_215_216.pdf (section 8.8,
+// password verification)
+package ntag215
+
+const (
+	// PageQuantity is the number of pages on a NTAG215 tag (0 to 134).
+	PageQuantity = 135
+	// PageSize is the size in bytes of a single page.
+	PageSize = 4
+	// DumpSize is the total size in bytes of a raw NTAG215 dump.
+	DumpSize = PageQuantity * PageSize
+
+	// PwdPage is the page holding the 4 byte password (PWD).
+	PwdPage = 133
+	// PackPage is the page holding the 2 byte password ack (PACK).
+	PackPage = 134
+)
+
+// UID is the 7 byte unique identifier of the tag.
+type UID [7]byte
+
+// PWD is the 4 byte password used to authenticate PWD_AUTH, stored on
+// page 133.
+type PWD [4]byte
+
+// PACK is the 2 byte password acknowledge returned on a successful
+// PWD_AUTH, stored on the low bytes of page 134. Every amiibo tag uses
+// the same fixed PACK; it is not derived from the UID.
+type PACK [2]byte
+
+// FixedPack is the constant PACK value used by every amiibo, per
+// turbospok's ntag215converter.py (see package doc comment) and every
+// other amiibo converter this tool was modeled on.
+var FixedPack = PACK{0x80, 0x80}
+
+// CalculatePassword derives PWD from the tag UID, following the formula
+// used by turbospok's ntag215converter.py (see package doc comment):
+//
+//	PWD0 = UID1 ^ UID3 ^ 0xAA
+//	PWD1 = UID2 ^ UID4 ^ 0x55
+//	PWD2 = UID3 ^ UID5 ^ 0xAA
+//	PWD3 = UID4 ^ UID6 ^ 0x55
+//
+// PACK is not UID-dependent; it is always FixedPack.
+func CalculatePassword(uid UID) (PWD, PACK) {
+	pwd := PWD{
+		uid[1] ^ uid[3] ^ 0xAA,
+		uid[2] ^ uid[4] ^ 0x55,
+		uid[3] ^ uid[5] ^ 0xAA,
+		uid[4] ^ uid[6] ^ 0x55,
+	}
+	return pwd, FixedPack
+}
+
+// PwdPageBytes returns the 4 bytes to write on page 133 (PWD).
+func PwdPageBytes(pwd PWD) [4]byte {
+	return [4]byte(pwd)
+}
+
+// PackPageBytes returns the 4 bytes to write on page 134: PACK followed
+// by the two reserved bytes, always zero on NTAG215.
+func PackPageBytes(pack PACK) [4]byte {
+	return [4]byte{pack[0], pack[1], 0x00, 0x00}
+}
+
+// ExtractUID reads the 7 byte UID out of a raw NTAG215 dump: bytes 0-2
+// and 4-7 (the BCC checksum bytes at 3 and 8 are skipped). dump must be
+// at least 8 bytes long.
+func ExtractUID(dump []byte) UID {
+	var uid UID
+	copy(uid[:3], dump[0:3])
+	copy(uid[3:], dump[4:8])
+	return uid
+}
+
+// RecomputePasswordPages overwrites pages 133 (PWD) and 134 (PACK) of
+// dump in place with the values derived from its own UID (see
+// CalculatePassword). dump must be at least DumpSize bytes long.
+func RecomputePasswordPages(dump []byte) {
+	pwd, pack := CalculatePassword(ExtractUID(dump))
+	pwdBytes := PwdPageBytes(pwd)
+	packBytes := PackPageBytes(pack)
+	copy(dump[PwdPage*PageSize:PwdPage*PageSize+PageSize], pwdBytes[:])
+	copy(dump[PackPage*PageSize:PackPage*PageSize+PageSize], packBytes[:])
+}
@@ -0,0 +1,44 @@
+package ntag215
+
+import "testing"
+
+func TestCalculatePassword(t *testing.T) {
+	// UID/PWD vector computed independently from the formula in
+	// turbospok's ntag215converter.py (see package doc comment), not by
+	// re-running CalculatePassword itself: for UID 04 1F 2E 4A 5E 6D 80,
+	//
+	//	PWD0 = 0x1F ^ 0x4A ^ 0xAA = 0xFF
+	//	PWD1 = 0x2E ^ 0x5E ^ 0x55 = 0x25
+	//	PWD2 = 0x4A ^ 0x6D ^ 0xAA = 0x8D
+	//	PWD3 = 0x5E ^ 0x80 ^ 0x55 = 0x8B
+	uid := UID{0x04, 0x1f, 0x2e, 0x4a, 0x5e, 0x6d, 0x80}
+	wantPwd := PWD{0xFF, 0x25, 0x8D, 0x8B}
+	wantPack := PACK{0x80, 0x80}
+
+	pwd, pack := CalculatePassword(uid)
+
+	if pwd != wantPwd {
+		t.Errorf("CalculatePassword() pwd = %X, want %X", pwd, wantPwd)
+	}
+	if pack != wantPack {
+		t.Errorf("CalculatePassword() pack = %X, want %X", pack, wantPack)
+	}
+}
+
+func TestPwdPageBytes(t *testing.T) {
+	pwd := PWD{0x01, 0x02, 0x03, 0x04}
+	got := PwdPageBytes(pwd)
+	want := [4]byte{0x01, 0x02, 0x03, 0x04}
+	if got != want {
+		t.Errorf("PwdPageBytes() = %X, want %X", got, want)
+	}
+}
+
+func TestPackPageBytes(t *testing.T) {
+	pack := PACK{0x80, 0x80}
+	got := PackPageBytes(pack)
+	want := [4]byte{0x80, 0x80, 0x00, 0x00}
+	if got != want {
+		t.Errorf("PackPageBytes() = %X, want %X", got, want)
+	}
+}